@@ -0,0 +1,176 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"fmt"
+	"io"
+)
+
+// Dump writes a debug representation of node to w, one node per line,
+// indented to show nesting. The format is not stable and is only meant
+// to help while developing or debugging the parser.
+func Dump(w io.Writer, node Node) {
+	d := dumper{w: w}
+	d.dump(node, 0)
+}
+
+type dumper struct {
+	w io.Writer
+}
+
+func (d *dumper) indentf(depth int, format string, args ...interface{}) {
+	for i := 0; i < depth; i++ {
+		fmt.Fprint(d.w, "    ")
+	}
+	fmt.Fprintf(d.w, format, args...)
+}
+
+func (d *dumper) dump(node Node, depth int) {
+	switch x := node.(type) {
+	case nil:
+		d.indentf(depth, "nil\n")
+	case *File:
+		d.indentf(depth, "File\n")
+		for _, s := range x.Stmts {
+			d.dump(s, depth+1)
+		}
+	case *Stmt:
+		d.indentf(depth, "Stmt Negated=%v Background=%v\n", x.Negated, x.Background)
+		for _, c := range x.Comments {
+			d.dump(c, depth+1)
+		}
+		d.dump(x.Cmd, depth+1)
+		for _, r := range x.Redirs {
+			d.dump(r, depth+1)
+		}
+	case *CallExpr:
+		d.indentf(depth, "CallExpr\n")
+		for _, a := range x.Assigns {
+			d.dump(a, depth+1)
+		}
+		for _, w := range x.Args {
+			d.dump(w, depth+1)
+		}
+	case *Assign:
+		d.indentf(depth, "Assign\n")
+		d.dump(x.Name, depth+1)
+		if x.Value != nil {
+			d.dump(x.Value, depth+1)
+		}
+	case *Redirect:
+		d.indentf(depth, "Redirect Op=%v\n", x.Op)
+		if x.N != nil {
+			d.dump(x.N, depth+1)
+		}
+		d.dump(x.Word, depth+1)
+		if x.Hdoc != nil {
+			d.dump(x.Hdoc, depth+1)
+		}
+	case *Pipeline:
+		d.indentf(depth, "Pipeline\n")
+		for _, s := range x.Stmts {
+			d.dump(s, depth+1)
+		}
+	case *BinaryCmd:
+		d.indentf(depth, "BinaryCmd Op=%v\n", x.Op)
+		d.dump(x.X, depth+1)
+		d.dump(x.Y, depth+1)
+	case *Block:
+		d.indentf(depth, "Block\n")
+		for _, s := range x.Stmts {
+			d.dump(s, depth+1)
+		}
+	case *Subshell:
+		d.indentf(depth, "Subshell\n")
+		for _, s := range x.Stmts {
+			d.dump(s, depth+1)
+		}
+	case *FuncDecl:
+		d.indentf(depth, "FuncDecl\n")
+		d.dump(x.Name, depth+1)
+		d.dump(x.Body, depth+1)
+	case *IfClause:
+		d.indentf(depth, "IfClause\n")
+		for _, s := range x.Cond {
+			d.dump(s, depth+1)
+		}
+		for _, s := range x.Then {
+			d.dump(s, depth+1)
+		}
+		for _, e := range x.Elifs {
+			d.dump(e, depth+1)
+		}
+		for _, s := range x.Else {
+			d.dump(s, depth+1)
+		}
+	case *Elif:
+		d.indentf(depth, "Elif\n")
+		for _, s := range x.Cond {
+			d.dump(s, depth+1)
+		}
+		for _, s := range x.Then {
+			d.dump(s, depth+1)
+		}
+	case *WhileClause:
+		d.indentf(depth, "WhileClause Until=%v\n", x.Until)
+		for _, s := range x.Cond {
+			d.dump(s, depth+1)
+		}
+		for _, s := range x.Do {
+			d.dump(s, depth+1)
+		}
+	case *ForClause:
+		d.indentf(depth, "ForClause\n")
+		d.dump(x.Name, depth+1)
+		for _, w := range x.Items {
+			d.dump(w, depth+1)
+		}
+		for _, s := range x.Do {
+			d.dump(s, depth+1)
+		}
+	case *CaseClause:
+		d.indentf(depth, "CaseClause\n")
+		d.dump(x.Word, depth+1)
+		for _, i := range x.Items {
+			d.dump(i, depth+1)
+		}
+	case *CaseItem:
+		d.indentf(depth, "CaseItem\n")
+		for _, w := range x.Patterns {
+			d.dump(w, depth+1)
+		}
+		for _, s := range x.Stmts {
+			d.dump(s, depth+1)
+		}
+	case *Word:
+		d.indentf(depth, "Word\n")
+		for _, p := range x.Parts {
+			d.dump(p, depth+1)
+		}
+	case *Lit:
+		d.indentf(depth, "Lit %q\n", x.Value)
+	case *SglQuoted:
+		d.indentf(depth, "SglQuoted %q\n", x.Value)
+	case *DblQuoted:
+		d.indentf(depth, "DblQuoted\n")
+		for _, p := range x.Parts {
+			d.dump(p, depth+1)
+		}
+	case *CmdSubst:
+		d.indentf(depth, "CmdSubst Backquotes=%v\n", x.Backquotes)
+		for _, s := range x.Stmts {
+			d.dump(s, depth+1)
+		}
+	case *ArithmExp:
+		d.indentf(depth, "ArithmExp %q\n", x.Text)
+	case *ParamExp:
+		d.indentf(depth, "ParamExp Short=%v Length=%v Rest=%q\n", x.Short, x.Length, x.Rest)
+		d.dump(x.Param, depth+1)
+	case Comment:
+		d.indentf(depth, "Comment %q\n", x.Text)
+	default:
+		d.indentf(depth, "%T\n", x)
+	}
+}
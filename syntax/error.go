@@ -0,0 +1,37 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error is a single syntax error produced by the parser, tied to the
+// position where it was found.
+type Error struct {
+	Pos  Pos
+	Text string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Text)
+}
+
+// ErrorList collects every syntax error found while parsing a single
+// source, so that all of them can be reported at once instead of
+// stopping at the first one.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s (and %d more errors)", l[0], len(l)-1)
+	return b.String()
+}
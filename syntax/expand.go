@@ -0,0 +1,317 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"strings"
+	"unicode"
+)
+
+// identStartRune reports whether r can start a shell variable name.
+func identStartRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// identRune reports whether r can appear in a shell variable name after
+// its first rune.
+func identRune(r rune) bool {
+	return identStartRune(r) || unicode.IsDigit(r)
+}
+
+// isShortParamStart reports whether r can follow a bare '$' to start a
+// brace-less parameter expansion, e.g. the 'x' in "$x" or the '1' in
+// "$1" or the '@' in "$@".
+func isShortParamStart(r rune) bool {
+	if identStartRune(r) || unicode.IsDigit(r) {
+		return true
+	}
+	switch r {
+	case '@', '#', '?', '$', '!', '*', '-':
+		return true
+	}
+	return false
+}
+
+// dollarPart parses a word part starting at a '$' that has already been
+// consumed from the reader; dollarPos is the position of that '$'.
+func (p *parser) dollarPart(dollarPos Pos) WordPart {
+	bs, err := p.sc.Peek(1)
+	if err != nil || len(bs) == 0 {
+		p.next()
+		return &Lit{ValuePos: dollarPos, Value: "$"}
+	}
+	switch bs[0] {
+	case '(':
+		p.sc.ReadByte()
+		if bs2, _ := p.sc.Peek(1); len(bs2) > 0 && bs2[0] == '(' {
+			p.sc.ReadByte()
+			return p.arithmExp(dollarPos)
+		}
+		return p.cmdSubstParen(dollarPos)
+	case '{':
+		p.sc.ReadByte()
+		return p.paramExpBraced(dollarPos)
+	default:
+		return p.paramExpShort(dollarPos)
+	}
+}
+
+// cmdSubstParen parses a "$(...)" command substitution; the "$(" has
+// already been consumed.
+func (p *parser) cmdSubstParen(dollarPos Pos) WordPart {
+	p.next()
+	stmts := p.stmtList(')')
+	right := p.curPos()
+	p.want(')')
+	return &CmdSubst{Left: dollarPos, Right: right, Stmts: stmts}
+}
+
+// arithmExp parses a "$((...))" arithmetic expansion; the "$((" has
+// already been consumed. The expression is kept as raw text, since
+// arithmetic has its own grammar that this parser does not build a tree
+// for.
+func (p *parser) arithmExp(dollarPos Pos) WordPart {
+	var sb strings.Builder
+	depth := 0
+	for {
+		runePos := p.sc.Pos()
+		r, err := p.sc.NextRune()
+		if err != nil {
+			break
+		}
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				r2, err2 := p.sc.NextRune()
+				if err2 == nil && r2 == ')' {
+					p.next()
+					return &ArithmExp{Left: dollarPos, Right: runePos, Text: sb.String()}
+				}
+				if err2 == nil {
+					p.sc.UnreadRune()
+				}
+				sb.WriteRune(r)
+				continue
+			}
+			depth--
+		}
+		sb.WriteRune(r)
+	}
+	right := p.sc.Pos()
+	p.next()
+	return &ArithmExp{Left: dollarPos, Right: right, Text: sb.String()}
+}
+
+// paramExpBraced parses a "${...}" parameter expansion; the "${" has
+// already been consumed. Only the leading "#" (length) flag and the
+// parameter name are given structure; anything else inside the braces
+// is kept as raw text in Rest, since the many expansion operators
+// ("${x:-y}", "${x/a/b}", ...) are out of scope here.
+func (p *parser) paramExpBraced(dollarPos Pos) WordPart {
+	pe := &ParamExp{Dollar: dollarPos}
+	if bs, _ := p.sc.Peek(1); len(bs) > 0 && bs[0] == '#' {
+		pe.Length = true
+		p.sc.ReadByte()
+	}
+	namePos := p.sc.Pos()
+	var name []byte
+	for {
+		bs, err := p.sc.Peek(1)
+		if err != nil || len(bs) == 0 || !identRune(rune(bs[0])) {
+			break
+		}
+		name = append(name, bs[0])
+		p.sc.ReadByte()
+	}
+	pe.Param = &Lit{ValuePos: namePos, Value: string(name)}
+	var rest []byte
+	for {
+		b, err := p.sc.ReadByte()
+		if err != nil {
+			break
+		}
+		if b == '}' {
+			break
+		}
+		rest = append(rest, b)
+	}
+	pe.Rest = string(rest)
+	pe.Rbrace = p.sc.Pos()
+	p.next()
+	return pe
+}
+
+// paramExpShort parses a brace-less parameter expansion such as "$foo",
+// "$1" or "$@", starting right after the '$'.
+func (p *parser) paramExpShort(dollarPos Pos) WordPart {
+	bs, err := p.sc.Peek(1)
+	if err != nil || len(bs) == 0 || !isShortParamStart(rune(bs[0])) {
+		p.next()
+		return &Lit{ValuePos: dollarPos, Value: "$"}
+	}
+	namePos := p.sc.Pos()
+	if !identStartRune(rune(bs[0])) {
+		b := bs[0]
+		p.sc.ReadByte()
+		p.next()
+		return &ParamExp{Dollar: dollarPos, Short: true, Param: &Lit{ValuePos: namePos, Value: string(b)}}
+	}
+	var name []byte
+	for {
+		bs, err := p.sc.Peek(1)
+		if err != nil || len(bs) == 0 || !identRune(rune(bs[0])) {
+			break
+		}
+		name = append(name, bs[0])
+		p.sc.ReadByte()
+	}
+	p.next()
+	return &ParamExp{Dollar: dollarPos, Short: true, Param: &Lit{ValuePos: namePos, Value: string(name)}}
+}
+
+// parseDQContent splits the raw text captured between a pair of double
+// quotes (or a heredoc body) into Lit, ParamExp, ArithmExp and CmdSubst
+// parts, so that expansions inside them are visible in the tree. base is
+// the position of the first byte of s.
+func parseDQContent(s string, base Pos) []WordPart {
+	var parts []WordPart
+	var lit []byte
+	pos := base
+
+	flushLit := func() {
+		if len(lit) > 0 {
+			parts = append(parts, &Lit{ValuePos: pos, Value: string(lit)})
+			pos = pos.After(len(lit))
+			lit = nil
+		}
+	}
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			lit = append(lit, s[i], s[i+1])
+			i += 2
+			continue
+		}
+		if c != '$' || i+1 >= len(s) {
+			lit = append(lit, c)
+			i++
+			continue
+		}
+		dollarPos := pos.After(len(lit))
+		switch s[i+1] {
+		case '(':
+			if i+2 < len(s) && s[i+2] == '(' {
+				end := strings.Index(s[i+3:], "))")
+				if end < 0 {
+					lit = append(lit, c)
+					i++
+					continue
+				}
+				flushLit()
+				text := s[i+3 : i+3+end]
+				total := len(text) + 5 // "$((" + text + "))"
+				parts = append(parts, &ArithmExp{Left: dollarPos, Right: dollarPos.After(total), Text: text})
+				pos = dollarPos.After(total)
+				i += total
+				continue
+			}
+			j, ok := matchParen(s, i+2)
+			if !ok {
+				lit = append(lit, c)
+				i++
+				continue
+			}
+			flushLit()
+			inner := s[i+2 : j-1]
+			nested, _ := Parse(strings.NewReader(inner), base.Filename)
+			var stmts []*Stmt
+			if nested != nil {
+				stmts = nested.Stmts
+			}
+			parts = append(parts, &CmdSubst{Left: dollarPos, Right: dollarPos.After(j - i), Stmts: stmts})
+			pos = dollarPos.After(j - i)
+			i = j
+		case '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				lit = append(lit, c)
+				i++
+				continue
+			}
+			flushLit()
+			body := s[i+2 : i+2+end]
+			length := strings.HasPrefix(body, "#")
+			name := body
+			if length {
+				name = body[1:]
+			}
+			nameLen := 0
+			for nameLen < len(name) && identRune(rune(name[nameLen])) {
+				nameLen++
+			}
+			namePos := dollarPos.After(2)
+			if length {
+				namePos = namePos.After(1)
+			}
+			total := end + 3 // "${" + body + "}"
+			pe := &ParamExp{
+				Dollar: dollarPos,
+				Length: length,
+				Param:  &Lit{ValuePos: namePos, Value: name[:nameLen]},
+				Rest:   name[nameLen:],
+				Rbrace: dollarPos.After(total - 1),
+			}
+			parts = append(parts, pe)
+			pos = dollarPos.After(total)
+			i += total
+		default:
+			r := rune(s[i+1])
+			if !isShortParamStart(r) {
+				lit = append(lit, c)
+				i++
+				continue
+			}
+			flushLit()
+			nameLen := 1
+			if identStartRune(r) {
+				for nameLen < len(s)-(i+1) && identRune(rune(s[i+1+nameLen])) {
+					nameLen++
+				}
+			}
+			name := s[i+1 : i+1+nameLen]
+			namePos := dollarPos.After(1)
+			parts = append(parts, &ParamExp{Dollar: dollarPos, Short: true, Param: &Lit{ValuePos: namePos, Value: name}})
+			pos = dollarPos.After(1 + nameLen)
+			i += 1 + nameLen
+		}
+	}
+	flushLit()
+	if len(parts) == 0 {
+		return []WordPart{&Lit{ValuePos: base, Value: ""}}
+	}
+	return parts
+}
+
+// matchParen finds the index right after the ')' matching the implicit
+// '(' that starts at start, honouring nested parens. It reports false if
+// no matching ')' is found.
+func matchParen(s string, start int) (int, bool) {
+	depth := 1
+	for j := start; j < len(s); j++ {
+		switch s[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import "strings"
+
+// pendingHeredoc is a heredoc redirection whose delimiter has been
+// parsed but whose body still needs to be read, once the line
+// containing the redirection has been fully consumed.
+type pendingHeredoc struct {
+	r      *Redirect
+	tag    string
+	quoted bool // delimiter was quoted, so the body is not expanded
+}
+
+// consumeHeredocs reads the bodies of all pending heredocs from the
+// lines immediately following the current position, matching Op to tell
+// "<<" from "<<-" (which also strips leading tabs from the body and the
+// delimiter line).
+func (p *parser) consumeHeredocs() {
+	docs := p.heredocs
+	p.heredocs = nil
+	for _, h := range docs {
+		bodyPos := p.curPos()
+		dashed := h.r.Op == DashHeredoc
+		var body strings.Builder
+		for {
+			content, _, err := p.sc.ReadLine()
+			check := content
+			if dashed {
+				check = strings.TrimLeft(content, "\t")
+			}
+			if check == h.tag {
+				break
+			}
+			if dashed {
+				content = strings.TrimLeft(content, "\t")
+			}
+			body.WriteString(content)
+			body.WriteByte('\n')
+			if err != nil {
+				// EOF was reached without finding the closing delimiter.
+				break
+			}
+		}
+		text := body.String()
+		if h.quoted {
+			h.r.Hdoc = &Word{Parts: []WordPart{&Lit{ValuePos: bodyPos, Value: text}}}
+		} else {
+			h.r.Hdoc = &Word{Parts: parseDQContent(text, bodyPos)}
+		}
+	}
+}
+
+// wordLit reconstructs the literal text of a word made up of Lit and
+// quoted parts, e.g. to recover a heredoc delimiter.
+// Expansions inside it are ignored, since delimiters don't expand.
+func wordLit(w *Word) string {
+	var b strings.Builder
+	for _, part := range w.Parts {
+		switch x := part.(type) {
+		case *Lit:
+			b.WriteString(x.Value)
+		case *SglQuoted:
+			b.WriteString(x.Value)
+		case *DblQuoted:
+			for _, ip := range x.Parts {
+				if l, ok := ip.(*Lit); ok {
+					b.WriteString(l.Value)
+				}
+			}
+		}
+	}
+	return b.String()
+}
+
+// wordHasQuotes reports whether w contains any quoted part.
+func wordHasQuotes(w *Word) bool {
+	for _, part := range w.Parts {
+		switch part.(type) {
+		case *SglQuoted, *DblQuoted:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,424 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import "mvdan.cc/sh/syntax/scanner"
+
+// Node represents a node within the shell syntax tree. Every node knows
+// the source span it came from, so that tools built on top of the
+// parser (linters, formatters, refactoring tools, ...) can work with
+// structured data instead of re-parsing or re-lexing the source.
+type Node interface {
+	Pos() Pos
+	End() Pos
+}
+
+// Command is implemented by the node types that can appear as the
+// command of a Stmt: CallExpr, Pipeline, BinaryCmd, Block, Subshell,
+// FuncDecl, IfClause, WhileClause, ForClause and CaseClause.
+type Command interface {
+	Node
+	commandNode()
+}
+
+// WordPart is implemented by the node types that can make up a Word:
+// Lit, SglQuoted, DblQuoted, CmdSubst, ArithmExp and ParamExp.
+type WordPart interface {
+	Node
+	wordPartNode()
+}
+
+// File is the root node of a parsed shell program.
+type File struct {
+	Name  string
+	Stmts []*Stmt
+}
+
+func (f *File) Pos() Pos {
+	if len(f.Stmts) == 0 {
+		return Pos{}
+	}
+	return f.Stmts[0].Pos()
+}
+
+func (f *File) End() Pos {
+	if len(f.Stmts) == 0 {
+		return Pos{}
+	}
+	return f.Stmts[len(f.Stmts)-1].End()
+}
+
+// Stmt is a single statement, wrapping a Command along with any
+// redirections and the background (&) and negation (!) modifiers that
+// apply to it. Comments holds any "#" comments found immediately before
+// the statement (or on the same line as the previous one), attached
+// here since that's the nearest node to them.
+type Stmt struct {
+	Position   Pos
+	Cmd        Command
+	Negated    bool
+	Background bool
+	Redirs     []*Redirect
+	Comments   []Comment
+}
+
+func (s *Stmt) Pos() Pos { return s.Position }
+func (s *Stmt) End() Pos {
+	if n := len(s.Redirs); n > 0 {
+		return s.Redirs[n-1].End()
+	}
+	if s.Cmd != nil {
+		return s.Cmd.End()
+	}
+	return s.Position
+}
+
+// CallExpr is a command call, e.g. "FOO=bar prog arg1 arg2". Assigns
+// holds any leading variable assignments and Args holds the words that
+// make up the command name and its arguments.
+type CallExpr struct {
+	Assigns []*Assign
+	Args    []*Word
+}
+
+func (*CallExpr) commandNode() {}
+
+func (c *CallExpr) Pos() Pos {
+	if len(c.Assigns) > 0 {
+		return c.Assigns[0].Pos()
+	}
+	if len(c.Args) > 0 {
+		return c.Args[0].Pos()
+	}
+	return Pos{}
+}
+
+func (c *CallExpr) End() Pos {
+	if n := len(c.Args); n > 0 {
+		return c.Args[n-1].End()
+	}
+	if n := len(c.Assigns); n > 0 {
+		return c.Assigns[n-1].End()
+	}
+	return Pos{}
+}
+
+// Assign is a single "name=value" assignment, as used by CallExpr.
+type Assign struct {
+	Name  *Lit
+	Value *Word
+}
+
+func (a *Assign) Pos() Pos { return a.Name.Pos() }
+func (a *Assign) End() Pos {
+	if a.Value != nil {
+		return a.Value.End()
+	}
+	return a.Name.End()
+}
+
+// RedirOp is the kind of redirection operator used by a Redirect.
+type RedirOp int
+
+const (
+	RedirOut    RedirOp = iota // >
+	AppOut                     // >>
+	DupOut                     // >&
+	RedirIn                    // <
+	Heredoc                    // <<
+	DashHeredoc                // <<-
+)
+
+func (op RedirOp) String() string {
+	switch op {
+	case RedirOut:
+		return ">"
+	case AppOut:
+		return ">>"
+	case DupOut:
+		return ">&"
+	case RedirIn:
+		return "<"
+	case Heredoc:
+		return "<<"
+	case DashHeredoc:
+		return "<<-"
+	default:
+		return "unknown"
+	}
+}
+
+// Redirect is a single redirection attached to a Stmt, such as
+// "2>&1" or "<file". For the Heredoc and DashHeredoc operators, Word
+// holds the (possibly quoted) delimiter and Hdoc holds the body that was
+// read from the lines following the command.
+type Redirect struct {
+	OpPos Pos
+	Op    RedirOp
+	N     *Word // optional file descriptor, e.g. the "2" in "2>file"
+	Word  *Word
+	Hdoc  *Word
+}
+
+func (r *Redirect) Pos() Pos { return r.OpPos }
+func (r *Redirect) End() Pos {
+	if r.Word != nil {
+		return r.Word.End()
+	}
+	return r.OpPos
+}
+
+// Pipeline is a chain of statements joined by "|".
+type Pipeline struct {
+	Stmts []*Stmt
+}
+
+func (*Pipeline) commandNode() {}
+func (p *Pipeline) Pos() Pos   { return p.Stmts[0].Pos() }
+func (p *Pipeline) End() Pos   { return p.Stmts[len(p.Stmts)-1].End() }
+
+// BinCmdOp is the kind of operator joining the two sides of a BinaryCmd.
+type BinCmdOp int
+
+const (
+	AndStmt BinCmdOp = iota // &&
+	OrStmt                  // ||
+)
+
+func (op BinCmdOp) String() string {
+	switch op {
+	case AndStmt:
+		return "&&"
+	case OrStmt:
+		return "||"
+	default:
+		return "unknown"
+	}
+}
+
+// BinaryCmd is two statements joined by "&&" or "||".
+type BinaryCmd struct {
+	OpPos Pos
+	Op    BinCmdOp
+	X, Y  *Stmt
+}
+
+func (*BinaryCmd) commandNode() {}
+func (b *BinaryCmd) Pos() Pos   { return b.X.Pos() }
+func (b *BinaryCmd) End() Pos   { return b.Y.End() }
+
+// Block is a "{ ... }" group of statements, run in the current shell.
+type Block struct {
+	Lbrace, Rbrace Pos
+	Stmts          []*Stmt
+}
+
+func (*Block) commandNode() {}
+func (b *Block) Pos() Pos   { return b.Lbrace }
+func (b *Block) End() Pos   { return b.Rbrace }
+
+// Subshell is a "( ... )" group of statements, run in a child shell.
+type Subshell struct {
+	Lparen, Rparen Pos
+	Stmts          []*Stmt
+}
+
+func (*Subshell) commandNode() {}
+func (s *Subshell) Pos() Pos   { return s.Lparen }
+func (s *Subshell) End() Pos   { return s.Rparen }
+
+// FuncDecl is a function definition, e.g. "name() { ... }".
+type FuncDecl struct {
+	Position Pos
+	Name     *Lit
+	Body     *Block
+}
+
+func (*FuncDecl) commandNode() {}
+func (f *FuncDecl) Pos() Pos   { return f.Position }
+func (f *FuncDecl) End() Pos   { return f.Body.End() }
+
+// IfClause is an "if ... then ... elif ... else ... fi" construct.
+type IfClause struct {
+	Position Pos // position of "if"
+	Cond     []*Stmt
+	Then     []*Stmt
+	Elifs    []*Elif
+	Else     []*Stmt
+	FiPos    Pos
+}
+
+func (*IfClause) commandNode() {}
+func (c *IfClause) Pos() Pos   { return c.Position }
+func (c *IfClause) End() Pos   { return c.FiPos }
+
+// Elif is a single "elif ... then ..." branch of an IfClause.
+type Elif struct {
+	Position Pos // position of "elif"
+	Cond     []*Stmt
+	Then     []*Stmt
+}
+
+func (e *Elif) Pos() Pos { return e.Position }
+func (e *Elif) End() Pos {
+	if n := len(e.Then); n > 0 {
+		return e.Then[n-1].End()
+	}
+	return e.Position
+}
+
+// WhileClause is a "while ... do ... done" or "until ... do ... done"
+// construct; Until tells the two apart.
+type WhileClause struct {
+	Position Pos // position of "while" or "until"
+	Until    bool
+	Cond     []*Stmt
+	Do       []*Stmt
+	DonePos  Pos
+}
+
+func (*WhileClause) commandNode() {}
+func (w *WhileClause) Pos() Pos   { return w.Position }
+func (w *WhileClause) End() Pos   { return w.DonePos }
+
+// ForClause is a "for name in items; do ... done" construct. Items is
+// nil when the "in items" part is omitted, in which case the loop
+// ranges over the positional parameters.
+type ForClause struct {
+	Position Pos // position of "for"
+	Name     *Lit
+	Items    []*Word
+	Do       []*Stmt
+	DonePos  Pos
+}
+
+func (*ForClause) commandNode() {}
+func (f *ForClause) Pos() Pos   { return f.Position }
+func (f *ForClause) End() Pos   { return f.DonePos }
+
+// CaseClause is a "case word in pat) ... ;; esac" construct.
+type CaseClause struct {
+	Position Pos // position of "case"
+	Word     *Word
+	Items    []*CaseItem
+	EsacPos  Pos
+}
+
+func (*CaseClause) commandNode() {}
+func (c *CaseClause) Pos() Pos   { return c.Position }
+func (c *CaseClause) End() Pos   { return c.EsacPos }
+
+// CaseItem is a single "pat1 | pat2) ... ;;" branch of a CaseClause.
+type CaseItem struct {
+	Patterns []*Word
+	Stmts    []*Stmt
+}
+
+func (c *CaseItem) Pos() Pos { return c.Patterns[0].Pos() }
+func (c *CaseItem) End() Pos {
+	if n := len(c.Stmts); n > 0 {
+		return c.Stmts[n-1].End()
+	}
+	return c.Patterns[len(c.Patterns)-1].End()
+}
+
+// Word is a sequence of adjoining word parts, e.g. the `foo"bar"` in
+// `foo"bar" baz`.
+type Word struct {
+	Parts []WordPart
+}
+
+func (w *Word) Pos() Pos { return w.Parts[0].Pos() }
+func (w *Word) End() Pos { return w.Parts[len(w.Parts)-1].End() }
+
+// Lit is an unquoted literal word part.
+type Lit struct {
+	ValuePos Pos
+	Value    string
+}
+
+func (*Lit) wordPartNode() {}
+func (l *Lit) Pos() Pos    { return l.ValuePos }
+func (l *Lit) End() Pos    { return l.ValuePos.After(len(l.Value)) }
+
+// SglQuoted is a single-quoted string, e.g. 'foo bar'. Its Value holds
+// the content between the quotes, without any escape processing since
+// single quotes disable all expansion.
+type SglQuoted struct {
+	Position Pos
+	Value    string
+}
+
+func (*SglQuoted) wordPartNode() {}
+func (q *SglQuoted) Pos() Pos    { return q.Position }
+func (q *SglQuoted) End() Pos {
+	return q.Position.After(len(q.Value) + 2)
+}
+
+// DblQuoted is a double-quoted string, e.g. "foo $bar". Its Parts may
+// themselves contain expansions, unlike SglQuoted.
+type DblQuoted struct {
+	Position Pos
+	Parts    []WordPart
+}
+
+func (*DblQuoted) wordPartNode() {}
+func (q *DblQuoted) Pos() Pos    { return q.Position }
+func (q *DblQuoted) End() Pos {
+	if len(q.Parts) == 0 {
+		return q.Position
+	}
+	return q.Parts[len(q.Parts)-1].End()
+}
+
+// CmdSubst is a command substitution, e.g. "$(foo bar)" or `foo bar`.
+// Backquotes tells the two forms apart.
+type CmdSubst struct {
+	Left, Right Pos
+	Stmts       []*Stmt
+	Backquotes  bool
+}
+
+func (*CmdSubst) wordPartNode() {}
+func (c *CmdSubst) Pos() Pos    { return c.Left }
+func (c *CmdSubst) End() Pos    { return c.Right }
+
+// ArithmExp is an arithmetic expansion, e.g. "$((1 + 2))". The
+// expression itself is kept as raw, unparsed text in Text; arithmetic
+// has its own grammar that is out of scope for the word parser.
+type ArithmExp struct {
+	Left, Right Pos
+	Text        string
+}
+
+func (*ArithmExp) wordPartNode() {}
+func (a *ArithmExp) Pos() Pos    { return a.Left }
+func (a *ArithmExp) End() Pos    { return a.Right }
+
+// ParamExp is a parameter expansion, e.g. "$foo", "${foo}",
+// "${#foo}" or "${foo:-bar}". Short is true for the brace-less forms
+// like "$foo" or "$1". Rest holds the raw text of any ${...} operator
+// following the name (e.g. ":-bar" or "/pat/repl"), since the many
+// parameter-expansion operators are out of scope for the word parser.
+type ParamExp struct {
+	Dollar Pos
+	Rbrace Pos // zero Pos for the Short form
+	Short  bool
+	Length bool // ${#foo}
+	Param  *Lit
+	Rest   string
+}
+
+// Comment is a "#" line comment, attached to the nearest Stmt that
+// follows it in the source.
+type Comment = scanner.Comment
+
+func (*ParamExp) wordPartNode() {}
+func (p *ParamExp) Pos() Pos    { return p.Dollar }
+func (p *ParamExp) End() Pos {
+	if p.Short {
+		return p.Param.End()
+	}
+	return p.Rbrace
+}
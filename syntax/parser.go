@@ -0,0 +1,569 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"mvdan.cc/sh/syntax/scanner"
+)
+
+// Parse reads and parses a shell program from r, returning the
+// resulting syntax tree. name is used to build position information and
+// error messages; it is typically the path of the file being parsed.
+//
+// Parse recovers from syntax errors by resynchronizing at the next '\n'
+// or ';' and keeps going, so that a single Parse call reports every
+// syntax error in the source instead of just the first one. The
+// returned *File is valid as far as it could be parsed even when err is
+// non-nil; err is either nil, an ErrorList of one or more syntax
+// errors, or an I/O error from r.
+func Parse(r io.Reader, name string) (*File, error) {
+	p := &parser{name: name}
+	p.sc.Init(r, name, func(pos Pos, msg string) {
+		p.posErr(pos, "%s", msg)
+		p.recover()
+	})
+	p.next()
+	f := &File{Name: name}
+	f.Stmts = p.stmtList(tEOF)
+	if err := p.sc.Err(); err != nil {
+		return f, err
+	}
+	if len(p.errs) > 0 {
+		return f, p.errs
+	}
+	return f, nil
+}
+
+// parser recursive-descends over the tokens produced by a scanner.Scanner,
+// building up a syntax tree. All the lexing itself lives in the scanner
+// package; the parser only decides what a sequence of tokens means.
+type parser struct {
+	sc   scanner.Scanner
+	name string
+
+	errs ErrorList // accumulated syntax errors, recovered from as parsing continues
+
+	tok    token
+	pos    Pos    // start position of the current token
+	val    string // literal text of the current token
+	quote  rune   // quote rune that produced the current string token, if any
+	spaced bool   // whether whitespace preceded the current token
+
+	heredocs []pendingHeredoc // heredocs awaiting their body, in order
+}
+
+// curPos returns the start position of the current token, p.tok.
+func (p *parser) curPos() Pos {
+	return p.pos
+}
+
+// next scans the next token into p.tok/p.val. If any heredocs are
+// pending and the current token is the '\n' ending the line that
+// declared them, their bodies are read first, starting right after
+// that '\n'.
+func (p *parser) next() {
+	if len(p.heredocs) > 0 && p.tok == '\n' {
+		p.consumeHeredocs()
+	}
+	p.pos, p.tok, p.val = p.sc.Scan()
+	p.quote = p.sc.Quote
+	p.spaced = p.sc.Spaced
+}
+
+// takeComments returns and clears any comments scanned since the last
+// call, so they can be attached to the next node that's about to be
+// parsed.
+func (p *parser) takeComments() []Comment {
+	return p.sc.TakeComments()
+}
+
+func (p *parser) got(tok token) bool {
+	if p.tok == tok {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) want(tok token) {
+	if p.tok != tok {
+		p.errWanted(tok)
+		return
+	}
+	p.next()
+}
+
+func (p *parser) gotNewlines() {
+	for p.got('\n') {
+	}
+}
+
+// posErr records a syntax error at pos, without touching the scanning
+// position; the caller decides whether and how to resynchronize.
+func (p *parser) posErr(pos Pos, format string, v ...interface{}) {
+	p.errs = append(p.errs, &Error{Pos: pos, Text: fmt.Sprintf(format, v...)})
+}
+
+// recover skips tokens until it finds a plausible place to resume
+// parsing a new statement: a newline, a semicolon, or EOF. This keeps a
+// single malformed statement from aborting the rest of the parse.
+func (p *parser) recover() {
+	for p.tok != tEOF && p.tok != '\n' && p.tok != ';' {
+		p.next()
+	}
+}
+
+func (p *parser) errUnexpected() {
+	p.posErr(p.curPos(), "unexpected token %s", p.tok.String())
+	p.recover()
+}
+
+func (p *parser) errWanted(tok token) {
+	p.posErr(p.curPos(), "unexpected token %s, wanted %s", p.tok.String(), tok.String())
+	p.recover()
+}
+
+// stmtList parses statements until stop is reached or EOF.
+func (p *parser) stmtList(stop token) []*Stmt {
+	var stmts []*Stmt
+	for p.tok != tEOF && p.tok != stop {
+		if p.got('\n') || p.got(';') {
+			continue
+		}
+		stmts = append(stmts, p.stmt())
+	}
+	return stmts
+}
+
+// stmt parses a single statement, including any "&&"/"||" chaining.
+func (p *parser) stmt() *Stmt {
+	s := p.stmtNoAndOr()
+	for {
+		switch p.tok {
+		case '&':
+			opPos := p.curPos()
+			p.next()
+			if p.tok == '&' {
+				p.next()
+				p.gotNewlines()
+				y := p.stmt()
+				s = &Stmt{
+					Position: s.Position,
+					Cmd:      &BinaryCmd{OpPos: opPos, Op: AndStmt, X: s, Y: y},
+				}
+				continue
+			}
+			s.Background = true
+			return s
+		case '|':
+			p.next()
+			if p.tok == '|' {
+				opPos := p.curPos()
+				p.next()
+				p.gotNewlines()
+				y := p.stmt()
+				s = &Stmt{
+					Position: s.Position,
+					Cmd:      &BinaryCmd{OpPos: opPos, Op: OrStmt, X: s, Y: y},
+				}
+				continue
+			}
+			p.gotNewlines()
+			next := p.stmtNoAndOr()
+			if pl, ok := s.Cmd.(*Pipeline); ok && len(s.Redirs) == 0 {
+				pl.Stmts = append(pl.Stmts, next)
+			} else {
+				s = &Stmt{
+					Position: s.Position,
+					Cmd:      &Pipeline{Stmts: []*Stmt{s, next}},
+				}
+			}
+			continue
+		default:
+			return s
+		}
+	}
+}
+
+// stmtNoAndOr parses a single command along with any trailing
+// redirections, stopping at '\n', '&', '|' or EOF, or right after
+// consuming a ';'.
+func (p *parser) stmtNoAndOr() *Stmt {
+	s := &Stmt{Position: p.curPos(), Comments: p.takeComments()}
+	s.Cmd = p.command()
+	call, _ := s.Cmd.(*CallExpr)
+	for {
+		switch p.tok {
+		case '>', '<':
+			// A run of digits written right before the operator, as in
+			// "2>&1", is the file descriptor being redirected rather
+			// than an argument of the call.
+			var fd *Word
+			if call != nil && !p.spaced && len(call.Args) > 0 {
+				last := call.Args[len(call.Args)-1]
+				if lit, ok := last.Parts[0].(*Lit); ok && len(last.Parts) == 1 &&
+					lit.Value != "" && strings.Trim(lit.Value, "0123456789") == "" {
+					fd = last
+					call.Args = call.Args[:len(call.Args)-1]
+				}
+			}
+			r := p.redirect()
+			r.N = fd
+			s.Redirs = append(s.Redirs, r)
+			// A redirect doesn't end a simple command; further
+			// words after it are still args of the same call, e.g.
+			// "echo a >f b".
+			if call != nil {
+				p.callArgs(call)
+			}
+		case ';':
+			p.next()
+			return s
+		default:
+			return s
+		}
+	}
+}
+
+func (p *parser) redirect() *Redirect {
+	r := &Redirect{OpPos: p.curPos(), Op: RedirIn}
+	switch p.tok {
+	case '>':
+		r.Op = RedirOut
+		p.next()
+		if p.tok == '>' {
+			r.Op = AppOut
+			p.next()
+		} else if p.tok == '&' {
+			r.Op = DupOut
+			p.next()
+		}
+		r.Word = p.word()
+	case '<':
+		r.Op = RedirIn
+		p.next()
+		if p.tok == '<' {
+			p.next()
+			if (p.tok == tIdent || p.tok == tString) && p.quote == 0 && strings.HasPrefix(p.val, "-") {
+				r.Op = DashHeredoc
+				p.val = p.val[1:]
+			} else {
+				r.Op = Heredoc
+			}
+			tagWord := p.word()
+			r.Word = tagWord
+			p.heredocs = append(p.heredocs, pendingHeredoc{
+				r:      r,
+				tag:    wordLit(tagWord),
+				quoted: wordHasQuotes(tagWord),
+			})
+			return r
+		}
+		r.Word = p.word()
+	}
+	return r
+}
+
+// atKeyword reports whether the current token is the reserved word kw.
+// Reserved words are only recognized at the specific grammar positions
+// that call atKeyword; the scanner itself has no notion of keywords, so
+// e.g. "echo if" still parses "if" as a plain argument.
+func (p *parser) atKeyword(kw string) bool {
+	return p.tok == tIdent && p.val == kw
+}
+
+// command parses a single simple or compound command, without any
+// trailing redirections or "&&"/"||"/"|" chaining.
+func (p *parser) command() Command {
+	switch {
+	case p.atKeyword("if"):
+		return p.ifClause()
+	case p.atKeyword("while"):
+		return p.whileClause(false)
+	case p.atKeyword("until"):
+		return p.whileClause(true)
+	case p.atKeyword("for"):
+		return p.forClause()
+	case p.atKeyword("case"):
+		return p.caseClause()
+	case p.tok == tIdent, p.tok == tString, p.tok == '$':
+		return p.call()
+	case p.tok == '{':
+		return p.block()
+	case p.tok == '(':
+		return p.subshell()
+	default:
+		p.errUnexpected()
+		return nil
+	}
+}
+
+// wantKeyword consumes the reserved word kw at the current (command)
+// position, recording a syntax error if it isn't there.
+func (p *parser) wantKeyword(kw string) {
+	if !p.atKeyword(kw) {
+		p.posErr(p.curPos(), "unexpected token %s, wanted %q", p.tok.String(), kw)
+		p.recover()
+		return
+	}
+	p.next()
+}
+
+// stmtsTill parses statements until the current token is one of the
+// reserved words in until, or EOF is reached.
+func (p *parser) stmtsTill(until ...string) []*Stmt {
+	var stmts []*Stmt
+loop:
+	for p.tok != tEOF {
+		for _, kw := range until {
+			if p.atKeyword(kw) {
+				break loop
+			}
+		}
+		if p.got('\n') || p.got(';') {
+			continue
+		}
+		stmts = append(stmts, p.stmt())
+	}
+	return stmts
+}
+
+// ifClause parses an "if ... then ... elif ... else ... fi" construct.
+// The leading "if" has not been consumed yet.
+func (p *parser) ifClause() Command {
+	c := &IfClause{Position: p.curPos()}
+	p.next() // "if"
+	c.Cond = p.stmtsTill("then")
+	p.wantKeyword("then")
+	c.Then = p.stmtsTill("elif", "else", "fi")
+	for p.atKeyword("elif") {
+		e := &Elif{Position: p.curPos()}
+		p.next()
+		e.Cond = p.stmtsTill("then")
+		p.wantKeyword("then")
+		e.Then = p.stmtsTill("elif", "else", "fi")
+		c.Elifs = append(c.Elifs, e)
+	}
+	if p.atKeyword("else") {
+		p.next()
+		c.Else = p.stmtsTill("fi")
+	}
+	c.FiPos = p.curPos()
+	p.wantKeyword("fi")
+	return c
+}
+
+// whileClause parses a "while ... do ... done" or "until ... do ...
+// done" construct. The leading keyword has not been consumed yet.
+func (p *parser) whileClause(until bool) Command {
+	c := &WhileClause{Position: p.curPos(), Until: until}
+	p.next() // "while" or "until"
+	c.Cond = p.stmtsTill("do")
+	p.wantKeyword("do")
+	c.Do = p.stmtsTill("done")
+	c.DonePos = p.curPos()
+	p.wantKeyword("done")
+	return c
+}
+
+// forClause parses a "for name [in items]; do ... done" construct. The
+// leading "for" has not been consumed yet.
+func (p *parser) forClause() Command {
+	c := &ForClause{Position: p.curPos()}
+	p.next() // "for"
+	if p.tok != tIdent {
+		p.errWanted(tIdent)
+		return c
+	}
+	namePos := p.curPos()
+	c.Name = &Lit{ValuePos: namePos, Value: p.val}
+	p.next()
+	if p.atKeyword("in") {
+		p.next()
+		for p.tok == tIdent || p.tok == tString || p.tok == '$' {
+			c.Items = append(c.Items, p.word())
+		}
+	}
+	for p.tok == ';' || p.tok == '\n' {
+		p.next()
+	}
+	p.wantKeyword("do")
+	c.Do = p.stmtsTill("done")
+	c.DonePos = p.curPos()
+	p.wantKeyword("done")
+	return c
+}
+
+// caseClause parses a "case word in pat) ... ;; esac" construct. The
+// leading "case" has not been consumed yet.
+func (p *parser) caseClause() Command {
+	c := &CaseClause{Position: p.curPos()}
+	p.next() // "case"
+	c.Word = p.word()
+	p.gotNewlines()
+	p.wantKeyword("in")
+	p.gotNewlines()
+	for p.tok != tEOF && !p.atKeyword("esac") {
+		item := &CaseItem{}
+		p.got('(')
+		item.Patterns = append(item.Patterns, p.word())
+		for p.got('|') {
+			item.Patterns = append(item.Patterns, p.word())
+		}
+		p.want(')')
+		p.gotNewlines()
+		for p.tok != tEOF && p.tok != ';' && !p.atKeyword("esac") {
+			item.Stmts = append(item.Stmts, p.stmt())
+			p.gotNewlines()
+		}
+		if p.tok == ';' {
+			// If the statement ended right before the ";;" on the same
+			// line, stmtNoAndOr already consumed its first ';', so the
+			// current token is the second and last one. If the
+			// statement instead ended at a newline (the common,
+			// multi-line case-item style), neither ';' has been
+			// consumed yet, so the raw byte right after this token is
+			// still the second ';'.
+			if bs, _ := p.sc.Peek(1); len(bs) > 0 && bs[0] == ';' {
+				p.next()
+			}
+			p.next()
+		}
+		p.gotNewlines()
+		c.Items = append(c.Items, item)
+	}
+	c.EsacPos = p.curPos()
+	p.wantKeyword("esac")
+	return c
+}
+
+// call parses a simple command: a run of words and leading assignments,
+// or the special "name() { ... }" function declaration form.
+func (p *parser) call() Command {
+	c := &CallExpr{}
+	for {
+		switch p.tok {
+		case tIdent:
+			namePos := p.curPos()
+			name := p.val
+			p.next()
+			if len(c.Args) == 0 && len(c.Assigns) == 0 && p.tok == '(' {
+				p.next()
+				p.want(')')
+				p.gotNewlines()
+				return &FuncDecl{
+					Position: namePos,
+					Name:     &Lit{ValuePos: namePos, Value: name},
+					Body:     p.block(),
+				}
+			}
+			if len(c.Args) == 0 && p.tok == '=' {
+				p.next()
+				var val *Word
+				if p.tok == tIdent || p.tok == tString || p.tok == '$' {
+					val = p.word()
+				}
+				c.Assigns = append(c.Assigns, &Assign{
+					Name:  &Lit{ValuePos: namePos, Value: name},
+					Value: val,
+				})
+				continue
+			}
+			c.Args = append(c.Args, p.wordFrom(&Lit{ValuePos: namePos, Value: name}))
+		case tString, '$':
+			c.Args = append(c.Args, p.word())
+		default:
+			return c
+		}
+	}
+}
+
+// callArgs appends any further plain-word arguments to c. It is used by
+// stmtNoAndOr to keep collecting a call's args after a redirect appears
+// in the middle of a statement, e.g. the "b" in "echo a >f b". Unlike
+// call's own loop, it never starts a FuncDecl or an Assign, since those
+// are only recognized as the very first word of a call.
+func (p *parser) callArgs(c *CallExpr) {
+	for {
+		switch p.tok {
+		case tIdent:
+			namePos := p.curPos()
+			name := p.val
+			p.next()
+			c.Args = append(c.Args, p.wordFrom(&Lit{ValuePos: namePos, Value: name}))
+		case tString, '$':
+			c.Args = append(c.Args, p.word())
+		default:
+			return
+		}
+	}
+}
+
+func (p *parser) block() *Block {
+	b := &Block{Lbrace: p.curPos()}
+	p.want('{')
+	b.Stmts = p.stmtList('}')
+	b.Rbrace = p.curPos()
+	p.want('}')
+	return b
+}
+
+// subshell parses a "( ... )" group, run in a child shell.
+func (p *parser) subshell() *Subshell {
+	s := &Subshell{Lparen: p.curPos()}
+	p.want('(')
+	s.Stmts = p.stmtList(')')
+	s.Rparen = p.curPos()
+	p.want(')')
+	return s
+}
+
+// word parses a single (possibly compound) word, joining adjoining
+// literal and quoted parts with no space between them.
+func (p *parser) word() *Word {
+	return p.wordFrom(p.wordPart())
+}
+
+// wordFrom builds a word whose first part has already been consumed,
+// continuing to join any further parts with no space before them.
+func (p *parser) wordFrom(first WordPart) *Word {
+	w := &Word{Parts: []WordPart{first}}
+	for !p.spaced && (p.tok == tIdent || p.tok == tString || p.tok == '$') {
+		w.Parts = append(w.Parts, p.wordPart())
+	}
+	return w
+}
+
+func (p *parser) wordPart() WordPart {
+	pos := p.curPos()
+	switch {
+	case p.tok == '$':
+		return p.dollarPart(pos)
+	case p.tok == tString && p.quote == '\'':
+		part := &SglQuoted{Position: pos, Value: p.val}
+		p.next()
+		return part
+	case p.tok == tString && p.quote == '"':
+		part := &DblQuoted{Position: pos, Parts: parseDQContent(p.val, pos.After(1))}
+		p.next()
+		return part
+	case p.tok == tString && p.quote == '`':
+		nested, _ := Parse(strings.NewReader(p.val), p.name)
+		var stmts []*Stmt
+		if nested != nil {
+			stmts = nested.Stmts
+		}
+		part := &CmdSubst{Left: pos, Right: pos.After(len(p.val) + 2), Stmts: stmts, Backquotes: true}
+		p.next()
+		return part
+	default:
+		part := &Lit{ValuePos: pos, Value: p.val}
+		p.next()
+		return part
+	}
+}
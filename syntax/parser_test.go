@@ -0,0 +1,250 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"strings"
+	"testing"
+)
+
+func parseStr(t *testing.T, src string) *File {
+	t.Helper()
+	f, err := Parse(strings.NewReader(src), "test")
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", src, err)
+	}
+	return f
+}
+
+func TestIfElifElse(t *testing.T) {
+	f := parseStr(t, `if a; then b; elif c; then d; else e; fi`)
+	if len(f.Stmts) != 1 {
+		t.Fatalf("want 1 top-level stmt, got %d", len(f.Stmts))
+	}
+	c, ok := f.Stmts[0].Cmd.(*IfClause)
+	if !ok {
+		t.Fatalf("want *IfClause, got %T", f.Stmts[0].Cmd)
+	}
+	if len(c.Elifs) != 1 {
+		t.Fatalf("want 1 elif, got %d", len(c.Elifs))
+	}
+	if len(c.Else) != 1 {
+		t.Fatalf("want 1 else stmt, got %d", len(c.Else))
+	}
+}
+
+func TestNestedControlFlow(t *testing.T) {
+	src := `
+for x in a b; do
+	if test "$x"; then
+		while true; do
+			case $x in
+			a) break ;;
+			esac
+		done
+	fi
+done
+`
+	f := parseStr(t, src)
+	if len(f.Stmts) != 1 {
+		t.Fatalf("want 1 top-level stmt, got %d", len(f.Stmts))
+	}
+	forC, ok := f.Stmts[0].Cmd.(*ForClause)
+	if !ok {
+		t.Fatalf("want *ForClause, got %T", f.Stmts[0].Cmd)
+	}
+	ifC, ok := forC.Do[0].Cmd.(*IfClause)
+	if !ok {
+		t.Fatalf("want *IfClause, got %T", forC.Do[0].Cmd)
+	}
+	whileC, ok := ifC.Then[0].Cmd.(*WhileClause)
+	if !ok {
+		t.Fatalf("want *WhileClause, got %T", ifC.Then[0].Cmd)
+	}
+	if _, ok := whileC.Do[0].Cmd.(*CaseClause); !ok {
+		t.Fatalf("want *CaseClause, got %T", whileC.Do[0].Cmd)
+	}
+}
+
+// TestOptionalSemicolon covers the corner case where the ';' before
+// "then" or "do" may be replaced by a newline.
+func TestOptionalSemicolon(t *testing.T) {
+	tests := []string{
+		"if a\nthen b\nfi",
+		"if a; then b; fi",
+		"while a\ndo b\ndone",
+		"while a; do b; done",
+		"for x in a\ndo b\ndone",
+	}
+	for _, src := range tests {
+		parseStr(t, src)
+	}
+}
+
+func TestWordExpansions(t *testing.T) {
+	f := parseStr(t, `echo "hi $name and ${other:-def} and $(echo sub) and $((1+2))"`)
+	call := f.Stmts[0].Cmd.(*CallExpr)
+	dq := call.Args[1].Parts[0].(*DblQuoted)
+	if len(dq.Parts) != 8 {
+		t.Fatalf("want 8 parts in the double-quoted word, got %d", len(dq.Parts))
+	}
+	if pe, ok := dq.Parts[1].(*ParamExp); !ok || pe.Param.Value != "name" {
+		t.Fatalf("want ParamExp \"name\", got %#v", dq.Parts[1])
+	}
+	if pe, ok := dq.Parts[3].(*ParamExp); !ok || pe.Param.Value != "other" || pe.Rest != ":-def" {
+		t.Fatalf("want ParamExp \"other\" with Rest \":-def\", got %#v", dq.Parts[3])
+	}
+	if _, ok := dq.Parts[5].(*CmdSubst); !ok {
+		t.Fatalf("want CmdSubst, got %T", dq.Parts[5])
+	}
+	if ae, ok := dq.Parts[7].(*ArithmExp); !ok || ae.Text != "1+2" {
+		t.Fatalf("want ArithmExp \"1+2\", got %#v", dq.Parts[7])
+	}
+}
+
+func TestBackquoteCmdSubst(t *testing.T) {
+	f := parseStr(t, "echo `echo sub`")
+	call := f.Stmts[0].Cmd.(*CallExpr)
+	cs, ok := call.Args[1].Parts[0].(*CmdSubst)
+	if !ok || !cs.Backquotes {
+		t.Fatalf("want backquoted CmdSubst, got %#v", call.Args[1].Parts[0])
+	}
+}
+
+func TestHeredoc(t *testing.T) {
+	f := parseStr(t, "cat <<EOF\nfoo $x\nbar\nEOF\necho after\n")
+	if len(f.Stmts) != 2 {
+		t.Fatalf("want 2 top-level stmts, got %d", len(f.Stmts))
+	}
+	redirs := f.Stmts[0].Redirs
+	if len(redirs) != 1 || redirs[0].Op != Heredoc {
+		t.Fatalf("want a single Heredoc redirect, got %#v", redirs)
+	}
+	hdoc := redirs[0].Hdoc
+	if len(hdoc.Parts) != 3 {
+		t.Fatalf("want 3 parts in the heredoc body, got %d", len(hdoc.Parts))
+	}
+	if _, ok := hdoc.Parts[1].(*ParamExp); !ok {
+		t.Fatalf("want ParamExp in the heredoc body, got %T", hdoc.Parts[1])
+	}
+	name := f.Stmts[1].Cmd.(*CallExpr).Args[0].Parts[0].(*Lit).Value
+	if name != "echo" {
+		t.Fatalf("want the statement after the heredoc to parse normally, got %q", name)
+	}
+}
+
+func TestDashHeredocStripsTabs(t *testing.T) {
+	f := parseStr(t, "cat <<-EOF\n\tindented\n\tEOF\n")
+	redirs := f.Stmts[0].Redirs
+	if len(redirs) != 1 || redirs[0].Op != DashHeredoc {
+		t.Fatalf("want a single DashHeredoc redirect, got %#v", redirs)
+	}
+	lit := redirs[0].Hdoc.Parts[0].(*Lit)
+	if lit.Value != "indented\n" {
+		t.Fatalf("want leading tabs stripped, got %q", lit.Value)
+	}
+}
+
+func TestQuotedHeredocDelimDisablesExpansion(t *testing.T) {
+	f := parseStr(t, "cat <<'EOF'\nraw $x\nEOF\n")
+	hdoc := f.Stmts[0].Redirs[0].Hdoc
+	if len(hdoc.Parts) != 1 {
+		t.Fatalf("want a single literal part, got %d", len(hdoc.Parts))
+	}
+	lit, ok := hdoc.Parts[0].(*Lit)
+	if !ok || lit.Value != "raw $x\n" {
+		t.Fatalf("want the raw, unexpanded body, got %#v", hdoc.Parts[0])
+	}
+}
+
+// TestPositions checks concrete line/col/offset values across a
+// multi-line script, covering both plain words and the param expansion
+// built by parseDQContent, rather than just token kinds and shapes.
+func TestPositions(t *testing.T) {
+	src := "foo bar\n# a comment\nbaz \"$x\"\n"
+	f := parseStr(t, src)
+	if len(f.Stmts) != 2 {
+		t.Fatalf("want 2 top-level stmts, got %d", len(f.Stmts))
+	}
+
+	call1 := f.Stmts[0].Cmd.(*CallExpr)
+	foo := call1.Args[0].Parts[0].(*Lit)
+	bar := call1.Args[1].Parts[0].(*Lit)
+	checkPos(t, "foo", foo.Pos(), 1, 1, 0)
+	checkPos(t, "bar", bar.Pos(), 1, 5, 4)
+
+	call2 := f.Stmts[1].Cmd.(*CallExpr)
+	if len(f.Stmts[1].Comments) != 1 {
+		t.Fatalf("want 1 comment attached to the second stmt, got %d", len(f.Stmts[1].Comments))
+	}
+	checkPos(t, "comment hash", f.Stmts[1].Comments[0].Pos(), 2, 1, 8)
+	baz := call2.Args[0].Parts[0].(*Lit)
+	checkPos(t, "baz", baz.Pos(), 3, 1, 20)
+
+	dq := call2.Args[1].Parts[0].(*DblQuoted)
+	pe := dq.Parts[0].(*ParamExp)
+	checkPos(t, "$x", pe.Pos(), 3, 6, 25)
+}
+
+func checkPos(t *testing.T, name string, got Pos, line, col uint, offset int) {
+	t.Helper()
+	want := Pos{Filename: "test", Line: line, Col: col, Offset: offset}
+	if got != want {
+		t.Errorf("%s: got line=%d col=%d offset=%d, want line=%d col=%d offset=%d",
+			name, got.Line, got.Col, got.Offset, want.Line, want.Col, want.Offset)
+	}
+}
+
+// TestCommentEndsStatement checks that a trailing "#" comment doesn't
+// swallow the newline that separates it from the next statement.
+func TestCommentEndsStatement(t *testing.T) {
+	f := parseStr(t, "echo hi # c\necho bye\n")
+	if len(f.Stmts) != 2 {
+		t.Fatalf("want 2 top-level stmts, got %d", len(f.Stmts))
+	}
+	name := f.Stmts[1].Cmd.(*CallExpr).Args[0].Parts[0].(*Lit).Value
+	if name != "echo" {
+		t.Fatalf("want the statement after the comment to parse normally, got %q", name)
+	}
+}
+
+// TestPositionAfterMultilineQuote checks that a newline embedded in a
+// quoted string is still reflected in the position of later tokens.
+func TestPositionAfterMultilineQuote(t *testing.T) {
+	f := parseStr(t, "a \"x\ny\" b\n")
+	call := f.Stmts[0].Cmd.(*CallExpr)
+	b := call.Args[2].Parts[0].(*Lit)
+	checkPos(t, "b", b.Pos(), 2, 4, 8)
+}
+
+// TestArgAfterRedirect checks that a word following a redirect is still
+// part of the same statement's args, rather than starting a new one.
+func TestArgAfterRedirect(t *testing.T) {
+	f := parseStr(t, "echo a >f b\n")
+	if len(f.Stmts) != 1 {
+		t.Fatalf("want 1 top-level stmt, got %d", len(f.Stmts))
+	}
+	call := f.Stmts[0].Cmd.(*CallExpr)
+	if len(call.Args) != 3 {
+		t.Fatalf("want 3 args, got %d", len(call.Args))
+	}
+	if len(f.Stmts[0].Redirs) != 1 {
+		t.Fatalf("want 1 redirect, got %d", len(f.Stmts[0].Redirs))
+	}
+	last := call.Args[2].Parts[0].(*Lit).Value
+	if last != "b" {
+		t.Fatalf("want the last arg to be %q, got %q", "b", last)
+	}
+}
+
+func TestKeywordAsArgument(t *testing.T) {
+	f := parseStr(t, "echo if then done\n")
+	call, ok := f.Stmts[0].Cmd.(*CallExpr)
+	if !ok {
+		t.Fatalf("want *CallExpr, got %T", f.Stmts[0].Cmd)
+	}
+	if len(call.Args) != 4 {
+		t.Fatalf("want 4 args, got %d", len(call.Args))
+	}
+}
@@ -0,0 +1,11 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import "mvdan.cc/sh/syntax/scanner"
+
+// Pos describes the source location of a single token or node: the
+// file it came from, its 1-based line and column, and its 0-based byte
+// offset within the file.
+type Pos = scanner.Pos
@@ -0,0 +1,388 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"io"
+	"strings"
+)
+
+// PrintConfig configures how Print renders a syntax tree back to shell
+// source. The zero value prints with no indentation and no line
+// folding, which is still valid, round-trippable output.
+type PrintConfig struct {
+	Indent     int  // spaces per nesting level, used when Tabs is false
+	Tabs       bool // indent nesting levels with tabs instead of Indent spaces
+	KeepQuotes bool // preserve each word's original quoting; if false, single-quoted parts are normalized to escaped double quotes
+	MaxWidth   int  // fold a pipeline or "&&"/"||" chain after its operator once its line would exceed this width; 0 disables folding
+}
+
+// Print writes node to w as shell source, formatted according to cfg.
+// Formatting is idempotent: formatting the result of a previous Print
+// call with the same cfg reproduces it unchanged.
+func Print(w io.Writer, node Node, cfg PrintConfig) error {
+	p := &printer{cfg: cfg}
+	var out string
+	switch x := node.(type) {
+	case *File:
+		out = p.stmtListStr(0, x.Stmts)
+	case *Stmt:
+		out = p.indentStr(0) + p.stmtStr(x, 0) + "\n"
+	case Command:
+		out = p.commandStr(x, 0)
+	case *Word:
+		out = p.wordStr(x)
+	default:
+		out = ""
+	}
+	_, err := io.WriteString(w, out)
+	return err
+}
+
+type printer struct {
+	cfg PrintConfig
+}
+
+func (p *printer) unit() string {
+	if p.cfg.Tabs {
+		return "\t"
+	}
+	if p.cfg.Indent > 0 {
+		return strings.Repeat(" ", p.cfg.Indent)
+	}
+	return ""
+}
+
+func (p *printer) indentStr(depth int) string {
+	return strings.Repeat(p.unit(), depth)
+}
+
+// stmtListStr renders a block of statements, one per line, each
+// preceded by its leading comments and followed by the body of any
+// heredocs it redirects into.
+func (p *printer) stmtListStr(depth int, stmts []*Stmt) string {
+	var b strings.Builder
+	for _, s := range stmts {
+		for _, c := range s.Comments {
+			b.WriteString(p.indentStr(depth))
+			b.WriteString("#")
+			b.WriteString(c.Text)
+			b.WriteString("\n")
+		}
+		b.WriteString(p.indentStr(depth))
+		b.WriteString(p.stmtStr(s, depth))
+		b.WriteString("\n")
+		for _, r := range collectHeredocs(s) {
+			b.WriteString(p.heredocBodyStr(r))
+		}
+	}
+	return b.String()
+}
+
+// collectHeredocs returns every heredoc redirect within s, in source
+// order, including ones that belong to statements nested in a pipeline
+// or "&&"/"||" chain printed on the same logical line as s. Compound
+// commands (blocks, subshells, if/while/until/for/case) are not
+// descended into, since they print the heredocs of their own statements.
+func collectHeredocs(s *Stmt) []*Redirect {
+	var docs []*Redirect
+	Walk(s, func(n Node) bool {
+		switch x := n.(type) {
+		case *Redirect:
+			if x.Op == Heredoc || x.Op == DashHeredoc {
+				docs = append(docs, x)
+			}
+		case *Block, *Subshell, *IfClause, *WhileClause, *ForClause, *CaseClause:
+			return false
+		}
+		return true
+	})
+	return docs
+}
+
+func (p *printer) heredocBodyStr(r *Redirect) string {
+	var b strings.Builder
+	if r.Hdoc != nil {
+		b.WriteString(p.dqPartsStr(r.Hdoc.Parts))
+	}
+	b.WriteString(wordLit(r.Word))
+	b.WriteString("\n")
+	return b.String()
+}
+
+// inlineStmtsStr renders statements joined on a single line with "; ",
+// as used for the condition of an if/while/until and inside command
+// substitutions.
+func (p *printer) inlineStmtsStr(stmts []*Stmt) string {
+	parts := make([]string, len(stmts))
+	for i, s := range stmts {
+		parts[i] = p.stmtStr(s, 0)
+	}
+	return strings.Join(parts, "; ")
+}
+
+func (p *printer) stmtStr(s *Stmt, depth int) string {
+	var b strings.Builder
+	if s.Negated {
+		b.WriteString("! ")
+	}
+	b.WriteString(p.commandStr(s.Cmd, depth))
+	for _, r := range s.Redirs {
+		b.WriteString(" ")
+		b.WriteString(p.redirectStr(r))
+	}
+	if s.Background {
+		b.WriteString(" &")
+	}
+	return b.String()
+}
+
+func (p *printer) commandStr(cmd Command, depth int) string {
+	switch x := cmd.(type) {
+	case *CallExpr:
+		return p.callExprStr(x)
+	case *Pipeline:
+		return p.pipelineStr(x, depth)
+	case *BinaryCmd:
+		return p.binaryCmdStr(x, depth)
+	case *Block:
+		return "{\n" + p.stmtListStr(depth+1, x.Stmts) + p.indentStr(depth) + "}"
+	case *Subshell:
+		return "(\n" + p.stmtListStr(depth+1, x.Stmts) + p.indentStr(depth) + ")"
+	case *FuncDecl:
+		return x.Name.Value + "() " + p.commandStr(x.Body, depth)
+	case *IfClause:
+		return p.ifClauseStr(x, depth)
+	case *WhileClause:
+		return p.whileClauseStr(x, depth)
+	case *ForClause:
+		return p.forClauseStr(x, depth)
+	case *CaseClause:
+		return p.caseClauseStr(x, depth)
+	default:
+		return ""
+	}
+}
+
+func (p *printer) pipelineStr(x *Pipeline, depth int) string {
+	parts := make([]string, len(x.Stmts))
+	for i, s := range x.Stmts {
+		parts[i] = p.stmtStr(s, depth)
+	}
+	oneLine := strings.Join(parts, " | ")
+	if p.cfg.MaxWidth <= 0 || len(p.indentStr(depth))+len(oneLine) <= p.cfg.MaxWidth {
+		return oneLine
+	}
+	return strings.Join(parts, " |\n"+p.indentStr(depth+1))
+}
+
+func (p *printer) binaryCmdStr(x *BinaryCmd, depth int) string {
+	op := x.Op.String()
+	left := p.stmtStr(x.X, depth)
+	right := p.stmtStr(x.Y, depth)
+	oneLine := left + " " + op + " " + right
+	if p.cfg.MaxWidth <= 0 || len(p.indentStr(depth))+len(oneLine) <= p.cfg.MaxWidth {
+		return oneLine
+	}
+	return left + " " + op + "\n" + p.indentStr(depth+1) + right
+}
+
+func (p *printer) ifClauseStr(x *IfClause, depth int) string {
+	var b strings.Builder
+	b.WriteString("if ")
+	b.WriteString(p.inlineStmtsStr(x.Cond))
+	b.WriteString("; then\n")
+	b.WriteString(p.stmtListStr(depth+1, x.Then))
+	for _, e := range x.Elifs {
+		b.WriteString(p.indentStr(depth))
+		b.WriteString("elif ")
+		b.WriteString(p.inlineStmtsStr(e.Cond))
+		b.WriteString("; then\n")
+		b.WriteString(p.stmtListStr(depth+1, e.Then))
+	}
+	if len(x.Else) > 0 {
+		b.WriteString(p.indentStr(depth))
+		b.WriteString("else\n")
+		b.WriteString(p.stmtListStr(depth+1, x.Else))
+	}
+	b.WriteString(p.indentStr(depth))
+	b.WriteString("fi")
+	return b.String()
+}
+
+func (p *printer) whileClauseStr(x *WhileClause, depth int) string {
+	kw := "while"
+	if x.Until {
+		kw = "until"
+	}
+	var b strings.Builder
+	b.WriteString(kw)
+	b.WriteString(" ")
+	b.WriteString(p.inlineStmtsStr(x.Cond))
+	b.WriteString("; do\n")
+	b.WriteString(p.stmtListStr(depth+1, x.Do))
+	b.WriteString(p.indentStr(depth))
+	b.WriteString("done")
+	return b.String()
+}
+
+func (p *printer) forClauseStr(x *ForClause, depth int) string {
+	var b strings.Builder
+	b.WriteString("for ")
+	b.WriteString(x.Name.Value)
+	if len(x.Items) > 0 {
+		b.WriteString(" in")
+		for _, w := range x.Items {
+			b.WriteString(" ")
+			b.WriteString(p.wordStr(w))
+		}
+	}
+	b.WriteString("; do\n")
+	b.WriteString(p.stmtListStr(depth+1, x.Do))
+	b.WriteString(p.indentStr(depth))
+	b.WriteString("done")
+	return b.String()
+}
+
+func (p *printer) caseClauseStr(x *CaseClause, depth int) string {
+	var b strings.Builder
+	b.WriteString("case ")
+	b.WriteString(p.wordStr(x.Word))
+	b.WriteString(" in\n")
+	for _, item := range x.Items {
+		b.WriteString(p.indentStr(depth + 1))
+		pats := make([]string, len(item.Patterns))
+		for i, w := range item.Patterns {
+			pats[i] = p.wordStr(w)
+		}
+		b.WriteString(strings.Join(pats, " | "))
+		b.WriteString(")")
+		if len(item.Stmts) > 0 {
+			b.WriteString("\n")
+			b.WriteString(p.stmtListStr(depth+2, item.Stmts))
+			b.WriteString(p.indentStr(depth + 1))
+		} else {
+			b.WriteString(" ")
+		}
+		b.WriteString(";;\n")
+	}
+	b.WriteString(p.indentStr(depth))
+	b.WriteString("esac")
+	return b.String()
+}
+
+func (p *printer) callExprStr(c *CallExpr) string {
+	parts := make([]string, 0, len(c.Assigns)+len(c.Args))
+	for _, a := range c.Assigns {
+		parts = append(parts, p.assignStr(a))
+	}
+	for _, w := range c.Args {
+		parts = append(parts, p.wordStr(w))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (p *printer) assignStr(a *Assign) string {
+	if a.Value == nil {
+		return a.Name.Value + "="
+	}
+	return a.Name.Value + "=" + p.wordStr(a.Value)
+}
+
+func (p *printer) redirectStr(r *Redirect) string {
+	var b strings.Builder
+	if r.N != nil {
+		b.WriteString(p.wordStr(r.N))
+	}
+	b.WriteString(r.Op.String())
+	if r.Word != nil {
+		b.WriteString(p.wordStr(r.Word))
+	}
+	return b.String()
+}
+
+func (p *printer) wordStr(w *Word) string {
+	var b strings.Builder
+	for _, part := range w.Parts {
+		b.WriteString(p.wordPartStr(part))
+	}
+	return b.String()
+}
+
+func (p *printer) wordPartStr(part WordPart) string {
+	switch x := part.(type) {
+	case *Lit:
+		return x.Value
+	case *SglQuoted:
+		if p.cfg.KeepQuotes {
+			return "'" + x.Value + "'"
+		}
+		return "\"" + escapeDQ(x.Value) + "\""
+	case *DblQuoted:
+		return "\"" + p.dqPartsStr(x.Parts) + "\""
+	default:
+		return p.expnStr(part)
+	}
+}
+
+// dqPartsStr renders the parts found inside a double-quoted string or
+// an unquoted heredoc body: literal text interleaved with expansions.
+func (p *printer) dqPartsStr(parts []WordPart) string {
+	var b strings.Builder
+	for _, part := range parts {
+		b.WriteString(p.expnStr(part))
+	}
+	return b.String()
+}
+
+// expnStr renders a word part that can appear inside double quotes or
+// an unquoted heredoc body: a literal, or one of the $-expansion forms.
+func (p *printer) expnStr(part WordPart) string {
+	switch x := part.(type) {
+	case *Lit:
+		return x.Value
+	case *ParamExp:
+		return p.paramExpStr(x)
+	case *CmdSubst:
+		return p.cmdSubstStr(x)
+	case *ArithmExp:
+		return "$((" + x.Text + "))"
+	default:
+		return ""
+	}
+}
+
+func (p *printer) paramExpStr(x *ParamExp) string {
+	if x.Short {
+		return "$" + x.Param.Value
+	}
+	length := ""
+	if x.Length {
+		length = "#"
+	}
+	return "${" + length + x.Param.Value + x.Rest + "}"
+}
+
+func (p *printer) cmdSubstStr(x *CmdSubst) string {
+	inner := p.inlineStmtsStr(x.Stmts)
+	if x.Backquotes {
+		return "`" + inner + "`"
+	}
+	return "$(" + inner + ")"
+}
+
+// escapeDQ escapes the four runes that are special inside a
+// double-quoted string, for use when normalizing single-quoted text
+// into the double-quoted form.
+func escapeDQ(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '$', '`', '"':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func printStr(t *testing.T, f *File, cfg PrintConfig) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := Print(&buf, f, cfg); err != nil {
+		t.Fatalf("Print: %v", err)
+	}
+	return buf.String()
+}
+
+func TestPrintBasic(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{
+			"if a; then b; fi",
+			"if a; then\n  b\nfi\n",
+		},
+		{
+			"for x in a b; do echo $x; done",
+			"for x in a b; do\n  echo $x\ndone\n",
+		},
+		{
+			"foo | bar",
+			"foo | bar\n",
+		},
+		{
+			"(echo a; echo b)",
+			"(\n  echo a\n  echo b\n)\n",
+		},
+	}
+	for _, tc := range tests {
+		f := parseStr(t, tc.src)
+		got := printStr(t, f, PrintConfig{Indent: 2})
+		if got != tc.want {
+			t.Errorf("Print(%q) = %q, want %q", tc.src, got, tc.want)
+		}
+	}
+}
+
+func TestPrintIdempotent(t *testing.T) {
+	srcs := []string{
+		"if a; then b; elif c; then d; else e; fi\n",
+		"for x in a b c; do echo $x; done\n",
+		"while true; do echo hi; done\n",
+		"case $x in\na) foo ;;\nb|c) bar ;;\nesac\n",
+		`echo "hi $name and $(echo sub) and $((1+2))"` + "\n",
+		"cat <<EOF\nfoo $x\nbar\nEOF\necho after\n",
+		"# leading comment\necho hi\n",
+		"foo && bar || baz\n",
+		"name() {\n\techo hi\n}\n",
+		"(echo sub; echo shell)\n",
+	}
+	cfg := PrintConfig{Indent: 2}
+	for _, src := range srcs {
+		f1 := parseStr(t, src)
+		out1 := printStr(t, f1, cfg)
+
+		f2 := parseStr(t, out1)
+		out2 := printStr(t, f2, cfg)
+
+		if out1 != out2 {
+			t.Errorf("printing %q is not idempotent:\nfirst:  %q\nsecond: %q", src, out1, out2)
+		}
+	}
+}
+
+func TestPrintMaxWidthFoldsPipeline(t *testing.T) {
+	src := "echo aaaaaaaaaa | echo bbbbbbbbbb | echo cccccccccc\n"
+	f := parseStr(t, src)
+	got := printStr(t, f, PrintConfig{Indent: 2, MaxWidth: 20})
+	if !strings.Contains(got, "|\n") {
+		t.Errorf("Print with MaxWidth=20 did not fold pipeline, got %q", got)
+	}
+}
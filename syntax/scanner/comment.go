@@ -0,0 +1,14 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package scanner
+
+// Comment is a "#" line comment, found and set aside while scanning so
+// that a caller can attach it to whichever node it belongs to.
+type Comment struct {
+	Hash Pos
+	Text string
+}
+
+func (c Comment) Pos() Pos { return c.Hash }
+func (c Comment) End() Pos { return c.Hash.After(len(c.Text) + 1) }
@@ -0,0 +1,31 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package scanner
+
+import "fmt"
+
+// Pos describes the source location of a single token or node: the
+// file it came from, its 1-based line and column, and its 0-based byte
+// offset within the file.
+type Pos struct {
+	Filename  string
+	Line, Col uint
+	Offset    int
+}
+
+// String returns a human-readable representation of the position, such
+// as "foo.sh:3:5".
+func (p Pos) String() string {
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Col)
+}
+
+// After returns the position n bytes/columns after p, on the same line.
+func (p Pos) After(n int) Pos {
+	p.Col += uint(n)
+	p.Offset += n
+	return p
+}
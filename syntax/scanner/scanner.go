@@ -0,0 +1,339 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+// Package scanner implements lexical scanning of POSIX shell source,
+// turning a stream of bytes into a stream of tokens. It is used by
+// mvdan.cc/sh/syntax to build its parser, but is reusable on its own by
+// tools such as syntax highlighters or tokenizer-based linters that
+// don't need a full parse tree.
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+var reserved = map[rune]bool{
+	'\n': true,
+	'#':  true,
+	'=':  true,
+	'&':  true,
+	'>':  true,
+	'<':  true,
+	'|':  true,
+	';':  true,
+	'(':  true,
+	')':  true,
+	'{':  true,
+	'}':  true,
+	'$':  true,
+}
+
+var quote = map[rune]bool{
+	'"':  true,
+	'\'': true,
+	'`':  true,
+}
+
+var space = map[rune]bool{
+	' ':  true,
+	'\t': true,
+}
+
+var identRe = regexp.MustCompile(`^[a-zA-Z_]+[a-zA-Z0-9_]*$`)
+
+// Scanner reads a shell source and splits it into tokens, tracking
+// source positions as it goes. Use Init to start scanning a new source,
+// then call Scan repeatedly to retrieve each token.
+type Scanner struct {
+	r    *bufio.Reader
+	name string
+	errh func(Pos, string)
+
+	// Quote is the quote rune that produced the most recent String
+	// token, or 0 if it wasn't a quoted string.
+	Quote rune
+	// Spaced reports whether whitespace preceded the most recent token.
+	Spaced bool
+
+	line, col         int
+	offset            int
+	lastRuneSize      int
+	prevLine, prevCol int // line/col before the last NextRune, so UnreadRune can restore them exactly
+
+	comments []Comment
+	err      error // first I/O error seen from the reader, if any
+}
+
+// Init prepares the scanner to read from src, resetting any previous
+// state. filename is used to build position information and is
+// typically the path of the file being scanned. errh, if non-nil, is
+// called for every syntax error found while scanning, such as an
+// unterminated quote.
+func (s *Scanner) Init(src io.Reader, filename string, errh func(Pos, string)) {
+	*s = Scanner{r: bufio.NewReader(src), name: filename, errh: errh, line: 1, col: 1}
+}
+
+// Pos returns the scanner's current position in the source.
+func (s *Scanner) Pos() Pos {
+	return Pos{Filename: s.name, Line: uint(s.line), Col: uint(s.col), Offset: s.offset}
+}
+
+// Err returns the first I/O error encountered while reading the
+// source, if any. Syntax errors are reported through the errh callback
+// passed to Init instead.
+func (s *Scanner) Err() error { return s.err }
+
+func (s *Scanner) ioErr(err error) {
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+func (s *Scanner) error(pos Pos, format string, v ...interface{}) {
+	if s.errh != nil {
+		s.errh(pos, fmt.Sprintf(format, v...))
+	}
+}
+
+// advance moves the position counters past r, which was just read as
+// size bytes, treating a newline as starting a new line.
+func (s *Scanner) advance(r rune, size int) {
+	if r == '\n' {
+		s.line++
+		s.col = 1
+	} else {
+		s.col++
+	}
+	s.offset += size
+}
+
+// NextRune reads and returns the next rune from the source, advancing
+// the scanner's position. It is exposed so that callers needing to read
+// past what Scan models as tokens (e.g. the contents of an arithmetic
+// or parameter expansion) can keep doing so while staying in sync with
+// the scanner's position tracking.
+func (s *Scanner) NextRune() (rune, error) {
+	r, size, err := s.r.ReadRune()
+	if err != nil {
+		if err != io.EOF {
+			s.ioErr(err)
+		}
+		return 0, err
+	}
+	s.lastRuneSize = size
+	s.prevLine, s.prevCol = s.line, s.col
+	s.advance(r, size)
+	return r, nil
+}
+
+// UnreadRune unreads the last rune read via NextRune, as with
+// bufio.Reader.UnreadRune. It restores the exact line/col the scanner
+// was at beforehand, rather than guessing by one column, since the
+// unread rune may have been a newline.
+func (s *Scanner) UnreadRune() error {
+	if err := s.r.UnreadRune(); err != nil {
+		s.ioErr(err)
+		return err
+	}
+	s.offset -= s.lastRuneSize
+	s.line, s.col = s.prevLine, s.prevCol
+	return nil
+}
+
+// ReadByte reads and returns the next byte from the source, advancing
+// the scanner's position.
+func (s *Scanner) ReadByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err != nil {
+		if err != io.EOF {
+			s.ioErr(err)
+		}
+		return 0, err
+	}
+	s.advance(rune(b), 1)
+	return b, nil
+}
+
+// Peek returns the next n bytes from the source without advancing the
+// scanner, as with bufio.Reader.Peek.
+func (s *Scanner) Peek(n int) ([]byte, error) {
+	return s.r.Peek(n)
+}
+
+// ReadLine reads up to and including the next newline, returning the
+// line's content without the newline. hasNL is false only for a final,
+// unterminated line at EOF. It is used to read raw lines such as a
+// heredoc body, which aren't tokenized.
+func (s *Scanner) ReadLine() (line string, hasNL bool, err error) {
+	b, err := s.r.ReadString('\n')
+	hasNL = len(b) > 0 && b[len(b)-1] == '\n'
+	if hasNL {
+		line = b[:len(b)-1]
+	} else {
+		line = b
+	}
+	s.offset += len(b)
+	if hasNL {
+		s.line++
+		s.col = 1
+	} else {
+		s.col += len(line)
+	}
+	return line, hasNL, err
+}
+
+// TakeComments returns and clears any comments found since the last
+// call, so that a caller can attach them to whichever node comes next.
+func (s *Scanner) TakeComments() []Comment {
+	if len(s.comments) == 0 {
+		return nil
+	}
+	cs := s.comments
+	s.comments = nil
+	return cs
+}
+
+// Scan reads and returns the next token from the source, along with
+// its position and, for an Ident or String token, its literal text.
+// "#" comments are scanned over rather than returned as tokens; use
+// TakeComments to retrieve them.
+func (s *Scanner) Scan() (Pos, Token, string) {
+	s.Quote = 0
+	s.Spaced = false
+	r := ' '
+	var startPos Pos
+	for space[r] {
+		startPos = s.Pos()
+		nr, err := s.NextRune()
+		if err != nil {
+			return s.Pos(), EOF, ""
+		}
+		if space[nr] {
+			s.Spaced = true
+		}
+		r = nr
+	}
+	if r == '\\' {
+		nr, err := s.NextRune()
+		if err == nil && nr == '\n' {
+			return s.Scan()
+		}
+		if err == nil {
+			s.UnreadRune()
+		}
+	}
+	if reserved[r] {
+		if r == '#' {
+			hashPos := startPos
+			text, hitEOF := s.discardUpTo('\n')
+			s.comments = append(s.comments, Comment{Hash: hashPos, Text: text})
+			if hitEOF {
+				return s.Pos(), EOF, ""
+			}
+			return s.Scan()
+		}
+		return startPos, Token(r), ""
+	}
+	if quote[r] {
+		val := s.strContent(byte(r))
+		s.Quote = r
+		return startPos, String, val
+	}
+	tok, val := s.scanWord(r)
+	return startPos, tok, val
+}
+
+// scanWord scans a run of ordinary characters starting with first,
+// classifying the result as an Ident if it looks like a shell
+// identifier, or a plain String otherwise.
+func (s *Scanner) scanWord(first rune) (Token, string) {
+	runes := []rune{first}
+	r := first
+	hitEOF := false
+	for !reserved[r] && !quote[r] && !space[r] {
+		nr, err := s.NextRune()
+		if err == io.EOF {
+			hitEOF = true
+			break
+		}
+		if err != nil {
+			return EOF, ""
+		}
+		r = nr
+		runes = append(runes, r)
+	}
+	if !hitEOF && len(runes) > 1 {
+		if err := s.UnreadRune(); err != nil {
+			return EOF, ""
+		}
+		runes = runes[:len(runes)-1]
+	}
+	val := string(runes)
+	tok := String
+	if identRe.MatchString(val) {
+		tok = Ident
+	}
+	return tok, val
+}
+
+// strContent reads the content of a quoted string up to its closing
+// delim, returning the text between the quotes.
+func (s *Scanner) strContent(delim byte) string {
+	var content []byte
+	for {
+		b, err := s.r.ReadBytes(delim)
+		if err == io.EOF {
+			s.offset += len(b)
+			s.error(s.Pos(), "reached EOF, wanted closing %q", rune(delim))
+			return ""
+		} else if err != nil {
+			s.ioErr(err)
+			return ""
+		}
+		content = append(content, b...)
+		if delim == '\'' {
+			break
+		}
+		if len(b) > 1 && b[len(b)-2] == '\\' && b[len(b)-1] == delim {
+			continue
+		}
+		break
+	}
+	s.offset += len(content)
+	if nl := bytes.LastIndexByte(content, '\n'); nl >= 0 {
+		s.line += bytes.Count(content, []byte{'\n'})
+		s.col = len(content) - nl
+	} else {
+		s.col += len(content)
+	}
+	return string(content[:len(content)-1])
+}
+
+// discardUpTo reads up to but not including delim, returning the text
+// found and whether EOF was hit before delim was found. delim itself is
+// left unread, so callers that need it as a token can still read it.
+func (s *Scanner) discardUpTo(delim byte) (string, bool) {
+	var content []byte
+	for {
+		b, err := s.r.ReadByte()
+		if err == io.EOF {
+			s.offset += len(content)
+			return string(content), true
+		}
+		if err != nil {
+			s.ioErr(err)
+			s.offset += len(content)
+			return string(content), true
+		}
+		if b == delim {
+			s.r.UnreadByte()
+			s.offset += len(content)
+			return string(content), false
+		}
+		content = append(content, b)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package scanner
+
+import (
+	"strings"
+	"testing"
+)
+
+func scanAll(t *testing.T, src string) (toks []Token, vals []string) {
+	t.Helper()
+	var s Scanner
+	var errs []string
+	s.Init(strings.NewReader(src), "test", func(pos Pos, msg string) {
+		errs = append(errs, msg)
+	})
+	for {
+		_, tok, val := s.Scan()
+		if tok == EOF {
+			break
+		}
+		toks = append(toks, tok)
+		vals = append(vals, val)
+	}
+	if len(errs) > 0 {
+		t.Fatalf("Scan(%q) reported errors: %v", src, errs)
+	}
+	return toks, vals
+}
+
+func TestScanWords(t *testing.T) {
+	toks, vals := scanAll(t, "foo bar=baz")
+	want := []Token{Ident, Ident, Token('='), Ident}
+	if len(toks) != len(want) {
+		t.Fatalf("got %d tokens, want %d: %v", len(toks), len(want), toks)
+	}
+	for i, tok := range toks {
+		if tok != want[i] {
+			t.Errorf("token %d: got %v, want %v", i, tok, want[i])
+		}
+	}
+	if vals[0] != "foo" || vals[1] != "bar" || vals[3] != "baz" {
+		t.Errorf("got vals %v, want [foo bar _ baz]", vals)
+	}
+}
+
+func TestScanReservedAndQuote(t *testing.T) {
+	toks, vals := scanAll(t, `echo 'hi' | wc`)
+	wantToks := []Token{Ident, String, Token('|'), Ident}
+	wantVals := []string{"echo", "hi", "", "wc"}
+	if len(toks) != len(wantToks) {
+		t.Fatalf("got %d tokens, want %d: %v", len(toks), len(wantToks), toks)
+	}
+	for i, tok := range toks {
+		if tok != wantToks[i] || vals[i] != wantVals[i] {
+			t.Errorf("token %d: got (%v, %q), want (%v, %q)", i, tok, vals[i], wantToks[i], wantVals[i])
+		}
+	}
+}
+
+func TestScanUnterminatedQuoteErrors(t *testing.T) {
+	var s Scanner
+	var errs []string
+	s.Init(strings.NewReader(`echo "foo`), "test", func(pos Pos, msg string) {
+		errs = append(errs, msg)
+	})
+	for {
+		_, tok, _ := s.Scan()
+		if tok == EOF {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		t.Fatalf("expected an error for an unterminated quote, got none")
+	}
+}
+
+// TestSpacedOnlyAfterWhitespace checks that Spaced is only set for a
+// token that was actually preceded by whitespace, not for every token
+// that follows the whitespace-skipping loop in Scan.
+func TestSpacedOnlyAfterWhitespace(t *testing.T) {
+	var s Scanner
+	s.Init(strings.NewReader("foo bar"), "test", nil)
+
+	_, tok, val := s.Scan()
+	if tok != Ident || val != "foo" {
+		t.Fatalf("got (%v, %q), want (Ident, foo)", tok, val)
+	}
+	if s.Spaced {
+		t.Errorf("Spaced = true for the first token, want false")
+	}
+
+	_, tok, val = s.Scan()
+	if tok != Ident || val != "bar" {
+		t.Fatalf("got (%v, %q), want (Ident, bar)", tok, val)
+	}
+	if !s.Spaced {
+		t.Errorf("Spaced = false for a token preceded by whitespace, want true")
+	}
+}
+
+// TestScanCommentKeepsNewline checks that a "#" comment doesn't consume
+// the newline that ends it, since that newline is a statement separator
+// the parser relies on.
+func TestScanCommentKeepsNewline(t *testing.T) {
+	toks, _ := scanAll(t, "echo hi # comment\necho bye")
+	want := []Token{Ident, Ident, Token('\n'), Ident, Ident}
+	if len(toks) != len(want) {
+		t.Fatalf("got %d tokens, want %d: %v", len(toks), len(want), toks)
+	}
+	for i, tok := range toks {
+		if tok != want[i] {
+			t.Errorf("token %d: got %v, want %v", i, tok, want[i])
+		}
+	}
+}
+
+func TestTokenString(t *testing.T) {
+	if got := EOF.String(); got != "EOF" {
+		t.Errorf("EOF.String() = %q, want EOF", got)
+	}
+	if got := Token('|').String(); got != "|" {
+		t.Errorf("Token('|').String() = %q, want |", got)
+	}
+}
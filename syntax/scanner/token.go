@@ -0,0 +1,31 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package scanner
+
+// Token is the kind of lexical token produced by Scan. Single-byte
+// tokens use their own rune value, so that e.g. '|' and '&' don't need
+// dedicated constants.
+type Token int32
+
+const (
+	_ Token = -iota - 1
+	EOF
+	Ident
+	String
+)
+
+// String returns a human-readable name for the token, for use in error
+// messages.
+func (t Token) String() string {
+	switch t {
+	case EOF:
+		return "EOF"
+	case String:
+		return "string"
+	case Ident:
+		return "ident"
+	default:
+		return string(rune(t))
+	}
+}
@@ -0,0 +1,17 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import "mvdan.cc/sh/syntax/scanner"
+
+// token is the kind of lexical token produced by the parser's scanning
+// step. Single-byte tokens use their own rune value, so that e.g. '|'
+// and '&' don't need dedicated constants.
+type token = scanner.Token
+
+const (
+	tEOF    = scanner.EOF
+	tIdent  = scanner.Ident
+	tString = scanner.String
+)
@@ -0,0 +1,137 @@
+// Copyright (c) 2016, Daniel Martí <mvdan@mvdan.cc>
+// See LICENSE for licensing information
+
+package syntax
+
+import "fmt"
+
+// Walk traverses an AST in depth-first order: it starts by calling
+// fn(node); node must not be nil. If fn returns true, Walk invokes fn
+// recursively for each of the non-nil children of node. fn is never
+// called with nil.
+func Walk(node Node, fn func(Node) bool) {
+	if !fn(node) {
+		return
+	}
+	switch x := node.(type) {
+	case *File:
+		for _, s := range x.Stmts {
+			Walk(s, fn)
+		}
+	case *Stmt:
+		for _, c := range x.Comments {
+			Walk(c, fn)
+		}
+		if x.Cmd != nil {
+			Walk(x.Cmd, fn)
+		}
+		for _, r := range x.Redirs {
+			Walk(r, fn)
+		}
+	case *CallExpr:
+		for _, a := range x.Assigns {
+			Walk(a, fn)
+		}
+		for _, w := range x.Args {
+			Walk(w, fn)
+		}
+	case *Assign:
+		Walk(x.Name, fn)
+		if x.Value != nil {
+			Walk(x.Value, fn)
+		}
+	case *Redirect:
+		if x.N != nil {
+			Walk(x.N, fn)
+		}
+		if x.Word != nil {
+			Walk(x.Word, fn)
+		}
+		if x.Hdoc != nil {
+			Walk(x.Hdoc, fn)
+		}
+	case *Pipeline:
+		for _, s := range x.Stmts {
+			Walk(s, fn)
+		}
+	case *BinaryCmd:
+		Walk(x.X, fn)
+		Walk(x.Y, fn)
+	case *Block:
+		for _, s := range x.Stmts {
+			Walk(s, fn)
+		}
+	case *Subshell:
+		for _, s := range x.Stmts {
+			Walk(s, fn)
+		}
+	case *FuncDecl:
+		Walk(x.Name, fn)
+		Walk(x.Body, fn)
+	case *IfClause:
+		for _, s := range x.Cond {
+			Walk(s, fn)
+		}
+		for _, s := range x.Then {
+			Walk(s, fn)
+		}
+		for _, e := range x.Elifs {
+			Walk(e, fn)
+		}
+		for _, s := range x.Else {
+			Walk(s, fn)
+		}
+	case *Elif:
+		for _, s := range x.Cond {
+			Walk(s, fn)
+		}
+		for _, s := range x.Then {
+			Walk(s, fn)
+		}
+	case *WhileClause:
+		for _, s := range x.Cond {
+			Walk(s, fn)
+		}
+		for _, s := range x.Do {
+			Walk(s, fn)
+		}
+	case *ForClause:
+		Walk(x.Name, fn)
+		for _, w := range x.Items {
+			Walk(w, fn)
+		}
+		for _, s := range x.Do {
+			Walk(s, fn)
+		}
+	case *CaseClause:
+		Walk(x.Word, fn)
+		for _, i := range x.Items {
+			Walk(i, fn)
+		}
+	case *CaseItem:
+		for _, w := range x.Patterns {
+			Walk(w, fn)
+		}
+		for _, s := range x.Stmts {
+			Walk(s, fn)
+		}
+	case *Word:
+		for _, p := range x.Parts {
+			Walk(p, fn)
+		}
+	case *DblQuoted:
+		for _, p := range x.Parts {
+			Walk(p, fn)
+		}
+	case *CmdSubst:
+		for _, s := range x.Stmts {
+			Walk(s, fn)
+		}
+	case *ParamExp:
+		Walk(x.Param, fn)
+	case *Lit, *SglQuoted, *ArithmExp, Comment:
+		// leaf nodes, nothing to do
+	default:
+		panic(fmt.Sprintf("syntax.Walk: unexpected node type %T", x))
+	}
+}